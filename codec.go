@@ -0,0 +1,158 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Codec marshals and unmarshals a Go value to and from an SQS message body.
+// Queue.SendMessageValue and Queue.ReceiveMessageInto route the payload
+// through the Codec configured on Queue.Codec. Marshaled output that isn't
+// valid UTF-8 or holds characters SQS rejects, such as control bytes (e.g.
+// GobCodec, AvroCodec), is base64-encoded so it survives SQS's text-only
+// message body; output that is already valid text (e.g. identityCodec,
+// JSONCodec) is sent as-is.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// sqsCodecBase64Attribute flags, on the sent message, that the body was
+// base64-encoded because the codec's output wasn't text SQS accepts. Its
+// absence tells ReceiveMessageInto to pass the body to the codec unchanged.
+const sqsCodecBase64Attribute = "sqs-codec-base64"
+
+// identityCodec marshals string and []byte values as-is. It is the default
+// Codec, matching the behavior of SendMessage/ReceiveMessage before Codec
+// was introduced.
+type identityCodec struct{}
+
+func (identityCodec) Marshal(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return val, nil
+	default:
+		return nil, fmt.Errorf("sqs: identityCodec cannot marshal %T, want string or []byte", v)
+	}
+}
+
+func (identityCodec) Unmarshal(data []byte, v interface{}) error {
+	switch ptr := v.(type) {
+	case *string:
+		*ptr = string(data)
+		return nil
+	case *[]byte:
+		*ptr = data
+		return nil
+	default:
+		return fmt.Errorf("sqs: identityCodec cannot unmarshal into %T, want *string or *[]byte", v)
+	}
+}
+
+// JSONCodec marshals values with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec marshals values with encoding/gob.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// codec returns the Queue's configured Codec, defaulting to identityCodec.
+func (q *Queue) codec() Codec {
+	if q.Codec != nil {
+		return q.Codec
+	}
+	return identityCodec{}
+}
+
+// SendMessageValue marshals v through the Queue's Codec and sends the
+// result as the message body, base64-encoding it only if it isn't valid
+// UTF-8 text free of characters SQS rejects.
+func (q *Queue) SendMessageValue(v interface{}) (*SendMessageResponse, error) {
+	return q.SendMessageValueContext(context.Background(), v)
+}
+
+func (q *Queue) SendMessageValueContext(ctx context.Context, v interface{}) (*SendMessageResponse, error) {
+	data, err := q.codec().Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQS accepts only #x9, #xA, #xD, #x20-#xD7FF, #xE000-#xFFFD and
+	// #x10000-#x10FFFF in a message body; utf8.Valid already rules out
+	// surrogates, so only control bytes and U+FFFE/U+FFFF remain to check.
+	text := utf8.Valid(data)
+	for _, r := range string(data) {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0xFFFE || r == 0xFFFF {
+			text = false
+			break
+		}
+	}
+	if text {
+		return q.SendMessageContext(ctx, string(data))
+	}
+
+	body := base64.StdEncoding.EncodeToString(data)
+	attributes := map[string]MessageAttributeValue{
+		sqsCodecBase64Attribute: {DataType: "String", StringValue: "true"},
+	}
+	return q.SendMessageWithAttributesContext(ctx, body, attributes)
+}
+
+// ReceiveMessageInto receives a single message and unmarshals its body into
+// v through the Queue's Codec, base64-decoding first if SendMessageValue
+// marked the body as base64. It returns the received Message (so callers
+// can still DeleteMessage it), or a nil Message and nil error if the queue
+// had nothing to receive.
+func (q *Queue) ReceiveMessageInto(v interface{}) (*Message, error) {
+	return q.ReceiveMessageIntoContext(context.Background(), v)
+}
+
+func (q *Queue) ReceiveMessageIntoContext(ctx context.Context, v interface{}) (*Message, error) {
+	resp, err := q.ReceiveMessageContext(ctx, 1, 30)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Messages) == 0 {
+		return nil, nil
+	}
+	msg := &resp.Messages[0]
+
+	data := []byte(msg.Body)
+	if msg.messageAttribute(sqsCodecBase64Attribute) == "true" {
+		data, err = base64.StdEncoding.DecodeString(msg.Body)
+		if err != nil {
+			return msg, err
+		}
+	}
+
+	if err := q.codec().Unmarshal(data, v); err != nil {
+		return msg, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,42 @@
+// +build avro
+
+package sqs
+
+import (
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroCodec marshals values as Avro binary using the given codec schema.
+// It is only built with `-tags avro`, so the goavro dependency is not
+// forced on callers that don't need it.
+type AvroCodec struct {
+	Codec *goavro.Codec
+}
+
+// NewAvroCodec parses an Avro schema and returns a Codec for it.
+func NewAvroCodec(schema string) (*AvroCodec, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &AvroCodec{Codec: codec}, nil
+}
+
+func (c *AvroCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.Codec.BinaryFromNative(nil, v.(map[string]interface{}))
+}
+
+func (c *AvroCodec) Unmarshal(data []byte, v interface{}) error {
+	native, _, err := c.Codec.NativeFromBinary(data)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(*map[string]interface{})
+	if !ok {
+		return fmt.Errorf("sqs: AvroCodec.Unmarshal requires *map[string]interface{}, got %T", v)
+	}
+	*m = native.(map[string]interface{})
+	return nil
+}
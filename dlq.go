@@ -0,0 +1,123 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type SetQueueAttributesResponse struct {
+	ResponseMetadata ResponseMetadata
+}
+
+// SetQueueAttributes sets one or more queue attributes, e.g. RedrivePolicy
+// or VisibilityTimeout.
+func (q *Queue) SetQueueAttributes(attributes map[string]string) (*SetQueueAttributesResponse, error) {
+	return q.SetQueueAttributesContext(context.Background(), attributes)
+}
+
+func (q *Queue) SetQueueAttributesContext(ctx context.Context, attributes map[string]string) (resp *SetQueueAttributesResponse, err error) {
+	resp = &SetQueueAttributesResponse{}
+	params := makeParams("SetQueueAttributes")
+
+	idx := 1
+	for name, value := range attributes {
+		params[fmt.Sprintf("Attribute.%d.Name", idx)] = name
+		params[fmt.Sprintf("Attribute.%d.Value", idx)] = value
+		idx++
+	}
+
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
+	return
+}
+
+// RedrivePolicy is the JSON value of the SQS RedrivePolicy queue attribute.
+type RedrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     int    `json:"maxReceiveCount"`
+}
+
+// SetRedrivePolicy points the queue's dead-letter queue at deadLetterQueueArn,
+// moving messages there after maxReceiveCount failed receives.
+func (q *Queue) SetRedrivePolicy(deadLetterQueueArn string, maxReceiveCount int) (*SetQueueAttributesResponse, error) {
+	return q.SetRedrivePolicyContext(context.Background(), deadLetterQueueArn, maxReceiveCount)
+}
+
+func (q *Queue) SetRedrivePolicyContext(ctx context.Context, deadLetterQueueArn string, maxReceiveCount int) (*SetQueueAttributesResponse, error) {
+	data, err := json.Marshal(RedrivePolicy{
+		DeadLetterTargetArn: deadLetterQueueArn,
+		MaxReceiveCount:     maxReceiveCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return q.SetQueueAttributesContext(ctx, map[string]string{"RedrivePolicy": string(data)})
+}
+
+// GetRedrivePolicy returns the queue's RedrivePolicy, or nil if none is set.
+func (q *Queue) GetRedrivePolicy() (*RedrivePolicy, error) {
+	return q.GetRedrivePolicyContext(context.Background())
+}
+
+func (q *Queue) GetRedrivePolicyContext(ctx context.Context) (*RedrivePolicy, error) {
+	resp, err := q.GetQueueAttributesContext(ctx, "RedrivePolicy")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range resp.Attributes {
+		if a.Name != "RedrivePolicy" {
+			continue
+		}
+		var policy RedrivePolicy
+		if err := json.Unmarshal([]byte(a.Value), &policy); err != nil {
+			return nil, err
+		}
+		return &policy, nil
+	}
+	return nil, nil
+}
+
+// GetQueueArn returns q's ARN, which SetRedrivePolicy needs to wire up a DLQ
+// and which isn't otherwise derivable from the queue URL.
+func (s *SQS) GetQueueArn(q *Queue) (string, error) {
+	return s.GetQueueArnContext(context.Background(), q)
+}
+
+func (s *SQS) GetQueueArnContext(ctx context.Context, q *Queue) (string, error) {
+	resp, err := q.GetQueueAttributesContext(ctx, "QueueArn")
+	if err != nil {
+		return "", err
+	}
+
+	for _, a := range resp.Attributes {
+		if a.Name == "QueueArn" {
+			return a.Value, nil
+		}
+	}
+	return "", fmt.Errorf("sqs: QueueArn attribute not returned for queue %s", q.Url)
+}
+
+// CreateQueueWithDLQ creates a queue and points its RedrivePolicy at dlq in
+// one call.
+func (s *SQS) CreateQueueWithDLQ(name string, dlq *Queue, maxReceiveCount int) (*Queue, error) {
+	return s.CreateQueueWithDLQContext(context.Background(), name, dlq, maxReceiveCount)
+}
+
+func (s *SQS) CreateQueueWithDLQContext(ctx context.Context, name string, dlq *Queue, maxReceiveCount int) (*Queue, error) {
+	q, err := s.CreateQueueContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	dlqArn, err := s.GetQueueArnContext(ctx, dlq)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := q.SetRedrivePolicyContext(ctx, dlqArn, maxReceiveCount); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
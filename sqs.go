@@ -10,10 +10,14 @@
 package sqs
 
 import (
+	"context"
 	"net/http"
 	"net/http/httputil"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
 	"net/url"
+	"strings"
 	"time"
 	"fmt"
 	"log"
@@ -28,30 +32,54 @@ const debug = false
 type SQS struct {
 	aws.Auth
 	aws.Region
-	private byte // Reserve the right of using private data.
+	private    byte // Reserve the right of using private data.
+	HTTPClient *http.Client
+
+	// Protocol selects the wire protocol used to talk to SQS. The zero
+	// value, ProtocolQuery, is the legacy query+XML protocol this package
+	// has always spoken; ProtocolJSON speaks the AWS JSON 1.0 protocol.
+	Protocol Protocol
 }
 
 func New(auth aws.Auth, region aws.Region) *SQS {
-	return &SQS{auth, region, 0}
+	return &SQS{auth, region, 0, http.DefaultClient, ProtocolQuery}
+}
+
+// httpClient returns the configured HTTPClient, falling back to
+// http.DefaultClient when none was set.
+func (s *SQS) httpClient() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return http.DefaultClient
 }
 
 type Queue struct {
 	*SQS
 	Url string
+
+	// QueueReceiveAttributeNames overrides the system attributes
+	// ReceiveMessage requests on this queue; when empty, ReceiveMessage
+	// requests "All".
+	QueueReceiveAttributeNames []string
+
+	// Codec is used by SendMessageValue/ReceiveMessageInto to marshal and
+	// unmarshal message bodies; when nil it defaults to the identity codec.
+	Codec Codec
 }
 
 type CreateQueueResponse struct {
-	QueueUrl         string `xml:"CreateQueueResult>QueueUrl"`
+	QueueUrl         string `xml:"CreateQueueResult>QueueUrl" json:"QueueUrl"`
 	ResponseMetadata ResponseMetadata
 }
 
 type GetQueueUrlResponse struct {
-	QueueUrl         string `xml:"GetQueueUrlResult>QueueUrl"`
+	QueueUrl         string `xml:"GetQueueUrlResult>QueueUrl" json:"QueueUrl"`
 	ResponseMetadata ResponseMetadata
 }
 
 type ListQueuesResponse struct {
-	QueueUrl         []string `xml:"ListQueuesResult>QueueUrl"`
+	QueueUrl         []string `xml:"ListQueuesResult>QueueUrl" json:"QueueUrls"`
 	ResponseMetadata ResponseMetadata
 }
 
@@ -65,27 +93,135 @@ type DeleteQueueResponse struct {
 }
 
 type SendMessageResponse struct {
-	MD5              string `xml:"SendMessageResult>MD5OfMessageBody"`
-	Id               string `xml:"SendMessageResult>MessageId"`
+	MD5              string `xml:"SendMessageResult>MD5OfMessageBody" json:"MD5OfMessageBody"`
+	Id               string `xml:"SendMessageResult>MessageId" json:"MessageId"`
 	ResponseMetadata ResponseMetadata
 }
 
 type ReceiveMessageResponse struct {
-	Messages         []Message `xml:"ReceiveMessageResult>Message"`
+	Messages         []Message `xml:"ReceiveMessageResult>Message" json:"Messages"`
 	ResponseMetadata ResponseMetadata
 }
 
 type Message struct {
-	MessageId     string      `xml:"MessageId"`
-	Body          string      `xml:"Body"`
-	MD5OfBody     string      `xml:"MD5OfBody"`
-	ReceiptHandle string      `xml:"ReceiptHandle"`
-	Attribute     []Attribute `xml:"Attribute"`
+	MessageId        string               `xml:"MessageId" json:"MessageId"`
+	Body             string               `xml:"Body" json:"Body"`
+	MD5OfBody        string               `xml:"MD5OfBody" json:"MD5OfBody"`
+	ReceiptHandle    string               `xml:"ReceiptHandle" json:"ReceiptHandle"`
+	Attribute        AttributeList        `xml:"Attribute" json:"Attributes"`
+	MessageAttribute MessageAttributeList `xml:"MessageAttribute" json:"MessageAttributes"`
 }
 
 type Attribute struct {
-	Name  string `xml:"ReceiveMessageResult>Message>Attribute>Name"`
-	Value string `xml:"ReceiveMessageResult>Message>Attribute>Value"`
+	Name  string `xml:"Name" json:"Name"`
+	Value string `xml:"Value" json:"Value"`
+}
+
+// AttributeList is []Attribute that also decodes the AWS JSON 1.0
+// representation of system attributes, which is a {"Name": "Value"} object
+// rather than the XML protocol's list of Name/Value pairs.
+type AttributeList []Attribute
+
+func (al *AttributeList) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	list := make(AttributeList, 0, len(m))
+	for name, value := range m {
+		list = append(list, Attribute{Name: name, Value: value})
+	}
+	*al = list
+	return nil
+}
+
+// MessageAttributeList is []MessageAttribute that also decodes the AWS
+// JSON 1.0 representation of message attributes, which is a
+// {"Name": {"DataType": ..., "StringValue": ...}} object rather than the
+// XML protocol's list of Name/Value pairs.
+type MessageAttributeList []MessageAttribute
+
+func (mal *MessageAttributeList) UnmarshalJSON(data []byte) error {
+	var m map[string]MessageAttributeValue
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	list := make(MessageAttributeList, 0, len(m))
+	for name, value := range m {
+		list = append(list, MessageAttribute{Name: name, Value: value})
+	}
+	*mal = list
+	return nil
+}
+
+// attribute returns the value of the named system Attribute, or "" if absent.
+func (m *Message) attribute(name string) string {
+	for _, a := range m.Attribute {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// messageAttribute returns the StringValue of the named MessageAttribute,
+// or "" if absent.
+func (m *Message) messageAttribute(name string) string {
+	for _, a := range m.MessageAttribute {
+		if a.Name == name {
+			return a.Value.StringValue
+		}
+	}
+	return ""
+}
+
+// MessageGroupId returns the FIFO message group system attribute, if present.
+func (m *Message) MessageGroupId() string { return m.attribute("MessageGroupId") }
+
+// MessageDeduplicationId returns the FIFO deduplication system attribute, if present.
+func (m *Message) MessageDeduplicationId() string { return m.attribute("MessageDeduplicationId") }
+
+// SequenceNumber returns the FIFO sequence number system attribute, if present.
+func (m *Message) SequenceNumber() string { return m.attribute("SequenceNumber") }
+
+// MessageAttributeValue holds a single typed SQS message attribute, sent on
+// SendMessage and decoded back onto Message.MessageAttribute by ReceiveMessage.
+type MessageAttributeValue struct {
+	DataType    string `xml:"DataType" json:"DataType"`
+	StringValue string `xml:"StringValue" json:"StringValue"`
+	BinaryValue []byte `xml:"BinaryValue" json:"BinaryValue"`
+}
+
+// UnmarshalXML decodes BinaryValue from its base64 char data: encoding/xml
+// copies character data into a []byte field verbatim rather than
+// base64-decoding it the way encoding/json does, so without this the
+// receive side would see the base64 text instead of the original bytes.
+func (v *MessageAttributeValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var aux struct {
+		DataType    string `xml:"DataType"`
+		StringValue string `xml:"StringValue"`
+		BinaryValue string `xml:"BinaryValue"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+
+	v.DataType = aux.DataType
+	v.StringValue = aux.StringValue
+	v.BinaryValue = nil
+	if aux.BinaryValue != "" {
+		decoded, err := base64.StdEncoding.DecodeString(aux.BinaryValue)
+		if err != nil {
+			return err
+		}
+		v.BinaryValue = decoded
+	}
+	return nil
+}
+
+type MessageAttribute struct {
+	Name  string                `xml:"Name" json:"Name"`
+	Value MessageAttributeValue `xml:"Value" json:"Value"`
 }
 
 type ChangeMessageVisibilityResponse struct {
@@ -93,13 +229,13 @@ type ChangeMessageVisibilityResponse struct {
 }
 
 type GetQueueAttributesResponse struct {
-	Attributes       []Attribute `xml:"GetQueueAttributesResult>Attribute"`
+	Attributes       AttributeList `xml:"GetQueueAttributesResult>Attribute" json:"Attributes"`
 	ResponseMetadata ResponseMetadata
 }
 
 type ResponseMetadata struct {
-	RequestId string
-	BoxUsage  float64
+	RequestId string  `json:"RequestId"`
+	BoxUsage  float64 `json:"BoxUsage"`
 }
 
 type Error struct {
@@ -131,52 +267,99 @@ func (s *SQS) CreateQueue(queueName string) (*Queue, error) {
 	return s.CreateQueueWithTimeout(queueName, 30)
 }
 
-func (s *SQS) CreateQueueWithTimeout(queueName string, timeout int) (q *Queue, err error) {
-	resp, err := s.newQueue(queueName, timeout)
+func (s *SQS) CreateQueueContext(ctx context.Context, queueName string) (*Queue, error) {
+	return s.CreateQueueWithTimeoutContext(ctx, queueName, 30)
+}
+
+func (s *SQS) CreateQueueWithTimeout(queueName string, timeout int) (*Queue, error) {
+	return s.CreateQueueWithTimeoutContext(context.Background(), queueName, timeout)
+}
+
+func (s *SQS) CreateQueueWithTimeoutContext(ctx context.Context, queueName string, timeout int) (q *Queue, err error) {
+	resp, err := s.newQueue(ctx, queueName, timeout)
+	if err != nil {
+		return nil, err
+	}
+	q = &Queue{SQS: s, Url: resp.QueueUrl}
+	return
+}
+
+// CreateQueueWithAttributes creates a queue with arbitrary queue attributes,
+// e.g. FifoQueue="true" and ContentBasedDeduplication="true" for a .fifo queue.
+func (s *SQS) CreateQueueWithAttributes(queueName string, attributes map[string]string) (*Queue, error) {
+	return s.CreateQueueWithAttributesContext(context.Background(), queueName, attributes)
+}
+
+func (s *SQS) CreateQueueWithAttributesContext(ctx context.Context, queueName string, attributes map[string]string) (q *Queue, err error) {
+	resp, err := s.newQueueWithAttributes(ctx, queueName, attributes)
 	if err != nil {
 		return nil, err
 	}
-	q = &Queue{s, resp.QueueUrl}
+	q = &Queue{SQS: s, Url: resp.QueueUrl}
 	return
 }
 
 func (s *SQS) GetQueue(queueName string) (*Queue, error) {
+	return s.GetQueueContext(context.Background(), queueName)
+}
+
+func (s *SQS) GetQueueContext(ctx context.Context, queueName string) (*Queue, error) {
 	var q *Queue
-	resp, err := s.getQueueUrl(queueName)
+	resp, err := s.getQueueUrl(ctx, queueName)
 	if err != nil {
 		return q, err
 	}
-	q = &Queue{s, resp.QueueUrl}
+	q = &Queue{SQS: s, Url: resp.QueueUrl}
 	return q, nil
 }
 
 
 func (s *SQS) QueueFromArn(queueUrl string) (q *Queue) {
-	q = &Queue{s, queueUrl}
+	q = &Queue{SQS: s, Url: queueUrl}
 	return
 }
 
 
-func (s *SQS) getQueueUrl(queueName string) (resp *GetQueueUrlResponse, err error) {
+func (s *SQS) getQueueUrl(ctx context.Context, queueName string) (resp *GetQueueUrlResponse, err error) {
 	resp = &GetQueueUrlResponse{}
 	params := makeParams("GetQueueUrl")
 	params["QueueName"] = queueName
-	err = s.query("", params, resp)
+	err = s.queryContext(ctx, "", params, resp)
 	return resp, err
 }
 
-func (s *SQS) newQueue(queueName string, timeout int) (resp *CreateQueueResponse, err error) {
+func (s *SQS) newQueue(ctx context.Context, queueName string, timeout int) (resp *CreateQueueResponse, err error) {
 	resp = &CreateQueueResponse{}
 	params := makeParams("CreateQueue")
 
 	params["QueueName"] = queueName
 	params["DefaultVisibilityTimeout"] = strconv.Itoa(timeout)
 
-	err = s.query("", params, resp)
+	err = s.queryContext(ctx, "", params, resp)
+	return
+}
+
+func (s *SQS) newQueueWithAttributes(ctx context.Context, queueName string, attributes map[string]string) (resp *CreateQueueResponse, err error) {
+	resp = &CreateQueueResponse{}
+	params := makeParams("CreateQueue")
+
+	params["QueueName"] = queueName
+	idx := 1
+	for name, value := range attributes {
+		params[fmt.Sprintf("Attribute.%d.Name", idx)] = name
+		params[fmt.Sprintf("Attribute.%d.Value", idx)] = value
+		idx++
+	}
+
+	err = s.queryContext(ctx, "", params, resp)
 	return
 }
 
-func (s *SQS) ListQueues(QueueNamePrefix string) (resp *ListQueuesResponse, err error) {
+func (s *SQS) ListQueues(QueueNamePrefix string) (*ListQueuesResponse, error) {
+	return s.ListQueuesContext(context.Background(), QueueNamePrefix)
+}
+
+func (s *SQS) ListQueuesContext(ctx context.Context, QueueNamePrefix string) (resp *ListQueuesResponse, err error) {
 	resp = &ListQueuesResponse{}
 	params := makeParams("ListQueues")
 
@@ -184,121 +367,374 @@ func (s *SQS) ListQueues(QueueNamePrefix string) (resp *ListQueuesResponse, err
 		params["QueueNamePrefix"] = QueueNamePrefix
 	}
 
-	err = s.query("", params, resp)
+	err = s.queryContext(ctx, "", params, resp)
 	return
 }
 
-func (q *Queue) Delete() (resp *DeleteQueueResponse, err error) {
+func (q *Queue) Delete() (*DeleteQueueResponse, error) {
+	return q.DeleteContext(context.Background())
+}
+
+func (q *Queue) DeleteContext(ctx context.Context) (resp *DeleteQueueResponse, err error) {
 	resp = &DeleteQueueResponse{}
 	params := makeParams("DeleteQueue")
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
+	return
+}
+
+func (q *Queue) SendMessage(MessageBody string) (*SendMessageResponse, error) {
+	return q.SendMessageContext(context.Background(), MessageBody)
+}
+
+func (q *Queue) SendMessageContext(ctx context.Context, MessageBody string) (resp *SendMessageResponse, err error) {
+	resp = &SendMessageResponse{}
+	params := makeParams("SendMessage")
+
+	params["MessageBody"] = MessageBody
+
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
+	return
+}
+
+// SendMessageWithAttributes is like SendMessage but attaches typed
+// MessageAttribute metadata (e.g. for routing/filtering by consumers).
+func (q *Queue) SendMessageWithAttributes(MessageBody string, attributes map[string]MessageAttributeValue) (*SendMessageResponse, error) {
+	return q.SendMessageWithAttributesContext(context.Background(), MessageBody, attributes)
+}
+
+func (q *Queue) SendMessageWithAttributesContext(ctx context.Context, MessageBody string, attributes map[string]MessageAttributeValue) (resp *SendMessageResponse, err error) {
+	resp = &SendMessageResponse{}
+	params := makeParams("SendMessage")
+
+	params["MessageBody"] = MessageBody
+	addMessageAttributes(params, "MessageAttribute", attributes)
+
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
-func (q *Queue) SendMessage(MessageBody string) (resp *SendMessageResponse, err error) {
+// SendMessageFifo is SendMessage for a .fifo queue. MessageGroupId is
+// required for FIFO queues; MessageDeduplicationId may be omitted when the
+// queue has ContentBasedDeduplication enabled.
+func (q *Queue) SendMessageFifo(MessageBody, MessageGroupId, MessageDeduplicationId string) (*SendMessageResponse, error) {
+	return q.SendMessageFifoContext(context.Background(), MessageBody, MessageGroupId, MessageDeduplicationId)
+}
+
+func (q *Queue) SendMessageFifoContext(ctx context.Context, MessageBody, MessageGroupId, MessageDeduplicationId string) (resp *SendMessageResponse, err error) {
+	if isFifoQueue(q.Url) && MessageGroupId == "" {
+		return nil, fmt.Errorf("sqs: MessageGroupId is required to send to FIFO queue %s", q.Url)
+	}
+
 	resp = &SendMessageResponse{}
 	params := makeParams("SendMessage")
 
 	params["MessageBody"] = MessageBody
+	if MessageGroupId != "" {
+		params["MessageGroupId"] = MessageGroupId
+	}
+	if MessageDeduplicationId != "" {
+		params["MessageDeduplicationId"] = MessageDeduplicationId
+	}
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
-func (q *Queue) ReceiveMessage(MaxNumberOfMessages, VisibilityTimeoutSec int) (resp *ReceiveMessageResponse, err error) {
+// isFifoQueue reports whether a queue URL names a FIFO queue, which SQS
+// requires to end in the literal ".fifo" suffix.
+func isFifoQueue(queueUrl string) bool {
+	return strings.HasSuffix(queueUrl, ".fifo")
+}
+
+func (q *Queue) ReceiveMessage(MaxNumberOfMessages, VisibilityTimeoutSec int) (*ReceiveMessageResponse, error) {
+	return q.ReceiveMessageContext(context.Background(), MaxNumberOfMessages, VisibilityTimeoutSec)
+}
+
+func (q *Queue) ReceiveMessageContext(ctx context.Context, MaxNumberOfMessages, VisibilityTimeoutSec int) (*ReceiveMessageResponse, error) {
+	return q.receiveMessage(ctx, MaxNumberOfMessages, VisibilityTimeoutSec, 0)
+}
+
+// ReceiveMessageLongPoll is ReceiveMessage with long polling: the call
+// blocks server-side for up to waitSeconds (0-20) waiting for a message to
+// become available, instead of returning immediately when the queue is
+// empty. A ctx deadline or cancellation still tears down the request.
+func (q *Queue) ReceiveMessageLongPoll(max, visibilityTimeout, waitSeconds int) (*ReceiveMessageResponse, error) {
+	return q.ReceiveMessageLongPollContext(context.Background(), max, visibilityTimeout, waitSeconds)
+}
+
+func (q *Queue) ReceiveMessageLongPollContext(ctx context.Context, max, visibilityTimeout, waitSeconds int) (*ReceiveMessageResponse, error) {
+	return q.receiveMessage(ctx, max, visibilityTimeout, waitSeconds)
+}
+
+func (q *Queue) receiveMessage(ctx context.Context, MaxNumberOfMessages, VisibilityTimeoutSec, WaitTimeSeconds int) (resp *ReceiveMessageResponse, err error) {
 	resp = &ReceiveMessageResponse{}
 	params := makeParams("ReceiveMessage")
 
-	params["AttributeName"] = "All"
+	if len(q.QueueReceiveAttributeNames) > 0 {
+		for idx, name := range q.QueueReceiveAttributeNames {
+			params[fmt.Sprintf("AttributeName.%d", idx+1)] = name
+		}
+	} else {
+		params["AttributeName"] = "All"
+	}
+	params["MessageAttributeName"] = "All"
 	params["MaxNumberOfMessages"] = strconv.Itoa(MaxNumberOfMessages)
 	params["VisibilityTimeout"] = strconv.Itoa(VisibilityTimeoutSec)
+	if WaitTimeSeconds > 0 {
+		params["WaitTimeSeconds"] = strconv.Itoa(WaitTimeSeconds)
+	}
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
-func (q *Queue) ChangeMessageVisibility(M *Message, VisibilityTimeout int) (resp *ChangeMessageVisibilityResponse, err error) {
+// addMessageAttributes flattens a MessageAttributeValue map onto params
+// using the <prefix>.N.Name / .Value.DataType / .Value.StringValue|BinaryValue
+// scheme SQS expects for SendMessage and its batch variants.
+func addMessageAttributes(params map[string]string, prefix string, attributes map[string]MessageAttributeValue) {
+	idx := 1
+	for name, value := range attributes {
+		p := fmt.Sprintf("%s.%d", prefix, idx)
+		params[p+".Name"] = name
+		params[p+".Value.DataType"] = value.DataType
+		if value.StringValue != "" {
+			params[p+".Value.StringValue"] = value.StringValue
+		}
+		if len(value.BinaryValue) > 0 {
+			params[p+".Value.BinaryValue"] = base64.StdEncoding.EncodeToString(value.BinaryValue)
+		}
+		idx++
+	}
+}
+
+func (q *Queue) ChangeMessageVisibility(M *Message, VisibilityTimeout int) (*ChangeMessageVisibilityResponse, error) {
+	return q.ChangeMessageVisibilityContext(context.Background(), M, VisibilityTimeout)
+}
+
+func (q *Queue) ChangeMessageVisibilityContext(ctx context.Context, M *Message, VisibilityTimeout int) (resp *ChangeMessageVisibilityResponse, err error) {
 	resp = &ChangeMessageVisibilityResponse{}
 	params := makeParams("ChangeMessageVisibility")
 	params["VisibilityTimeout"] = strconv.Itoa(VisibilityTimeout)
 	params["ReceiptHandle"] = M.ReceiptHandle
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
-func (q *Queue) GetQueueAttributes(A string) (resp *GetQueueAttributesResponse, err error) {
+func (q *Queue) GetQueueAttributes(A string) (*GetQueueAttributesResponse, error) {
+	return q.GetQueueAttributesContext(context.Background(), A)
+}
+
+func (q *Queue) GetQueueAttributesContext(ctx context.Context, A string) (resp *GetQueueAttributesResponse, err error) {
 	resp = &GetQueueAttributesResponse{}
 	params := makeParams("GetQueueAttributes")
 	params["AttributeName"] = A
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
-func (q *Queue) DeleteMessage(M *Message) (resp *DeleteMessageResponse, err error) {
+func (q *Queue) DeleteMessage(M *Message) (*DeleteMessageResponse, error) {
+	return q.DeleteMessageContext(context.Background(), M)
+}
+
+func (q *Queue) DeleteMessageContext(ctx context.Context, M *Message) (resp *DeleteMessageResponse, err error) {
 	resp = &DeleteMessageResponse{}
 	params := makeParams("DeleteMessage")
 	params["ReceiptHandle"] = M.ReceiptHandle
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
+// maxBatchEntries is the number of entries SQS accepts in a single
+// SendMessageBatch/DeleteMessageBatch call; larger inputs are auto-chunked.
+const maxBatchEntries = 10
+
+// SendMessageBatchEntry is one message in a SendMessageBatch call. Id must
+// be unique within the call and is echoed back on the matching Successful
+// or Failed result entry.
+type SendMessageBatchEntry struct {
+	Id                     string
+	Body                   string
+	Attributes             map[string]MessageAttributeValue
+	DelaySeconds           int
+	MessageGroupId         string
+	MessageDeduplicationId string
+}
+
 type SendMessageBatchResultEntry struct {
 	Id               string `xml:"Id"`
 	MessageId        string `xml:"MessageId"`
 	MD5OfMessageBody string `xml:"MD5OfMessageBody"`
 }
 
+// BatchResultErrorEntry is one failed entry in a batch response, as returned
+// by SQS for both SendMessageBatch and DeleteMessageBatch.
+type BatchResultErrorEntry struct {
+	Id          string `xml:"Id"`
+	SenderFault bool   `xml:"SenderFault"`
+	Code        string `xml:"Code"`
+	Message     string `xml:"Message"`
+}
+
 type SendMessageBatchResponse struct {
-	SendMessageBatchResult []SendMessageBatchResultEntry `xml:"SendMessageBatchResult>SendMessageBatchResultEntry"`
-	ResponseMetadata       ResponseMetadata
+	Successful       []SendMessageBatchResultEntry `xml:"SendMessageBatchResult>SendMessageBatchResultEntry"`
+	Failed           []BatchResultErrorEntry       `xml:"SendMessageBatchResult>BatchResultErrorEntry"`
+	ResponseMetadata ResponseMetadata
 }
 
-/* SendMessageBatch 
+// PartialFailureError is returned alongside a batch response whenever at
+// least one entry failed; callers can inspect Failed to retry just those IDs.
+type PartialFailureError struct {
+	Failed []BatchResultErrorEntry
+}
+
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("sqs: %d batch entries failed, first %s: %s", len(e.Failed), e.Failed[0].Id, e.Failed[0].Message)
+}
+
+/* SendMessageBatch
  */
-func (q *Queue) SendMessageBatch(msgList []string) (resp *SendMessageBatchResponse, err error) {
+func (q *Queue) SendMessageBatch(entries []SendMessageBatchEntry) (*SendMessageBatchResponse, error) {
+	return q.SendMessageBatchContext(context.Background(), entries)
+}
+
+func (q *Queue) SendMessageBatchContext(ctx context.Context, entries []SendMessageBatchEntry) (resp *SendMessageBatchResponse, err error) {
+	resp = &SendMessageBatchResponse{}
+	for _, chunk := range chunkSendMessageBatchEntries(entries, maxBatchEntries) {
+		chunkResp, chunkErr := q.sendMessageBatchChunk(ctx, chunk)
+		if chunkErr != nil {
+			return resp, chunkErr
+		}
+		resp.Successful = append(resp.Successful, chunkResp.Successful...)
+		resp.Failed = append(resp.Failed, chunkResp.Failed...)
+		resp.ResponseMetadata = chunkResp.ResponseMetadata
+	}
+
+	if len(resp.Failed) > 0 {
+		return resp, &PartialFailureError{Failed: resp.Failed}
+	}
+	return resp, nil
+}
+
+func (q *Queue) sendMessageBatchChunk(ctx context.Context, entries []SendMessageBatchEntry) (resp *SendMessageBatchResponse, err error) {
 	resp = &SendMessageBatchResponse{}
 	params := makeParams("SendMessageBatch")
 
-	for idx, msg := range msgList {
-		count := idx + 1
-		params[fmt.Sprintf("SendMessageBatchRequestEntry.%d.Id", count)] = fmt.Sprintf("msg-%d", count)
-		params[fmt.Sprintf("SendMessageBatchRequestEntry.%d.MessageBody", count)] = msg
+	for idx, entry := range entries {
+		prefix := fmt.Sprintf("SendMessageBatchRequestEntry.%d", idx+1)
+		params[prefix+".Id"] = entry.Id
+		params[prefix+".MessageBody"] = entry.Body
+		if entry.DelaySeconds > 0 {
+			params[prefix+".DelaySeconds"] = strconv.Itoa(entry.DelaySeconds)
+		}
+		if entry.MessageGroupId != "" {
+			params[prefix+".MessageGroupId"] = entry.MessageGroupId
+		}
+		if entry.MessageDeduplicationId != "" {
+			params[prefix+".MessageDeduplicationId"] = entry.MessageDeduplicationId
+		}
+		if len(entry.Attributes) > 0 {
+			addMessageAttributes(params, prefix+".MessageAttribute", entry.Attributes)
+		}
 	}
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
+func chunkSendMessageBatchEntries(entries []SendMessageBatchEntry, size int) [][]SendMessageBatchEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	var chunks [][]SendMessageBatchEntry
+	for size < len(entries) {
+		entries, chunks = entries[size:], append(chunks, entries[0:size:size])
+	}
+	return append(chunks, entries)
+}
+
+// DeleteMessageBatchEntry is one message to delete in a DeleteMessageBatch
+// call. Id must be unique within the call and is echoed back on the
+// matching Successful or Failed result entry.
+type DeleteMessageBatchEntry struct {
+	Id            string
+	ReceiptHandle string
+}
+
+type DeleteMessageBatchResultEntry struct {
+	Id string `xml:"Id"`
+}
+
 type DeleteMessageBatchResponse struct {
-	DeleteMessageBatchResult []struct {
-		Id          string
-		SenderFault bool
-		Code        string
-		Message     string
-	}                `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry"`
+	Successful       []DeleteMessageBatchResultEntry `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry"`
+	Failed           []BatchResultErrorEntry         `xml:"DeleteMessageBatchResult>BatchResultErrorEntry"`
 	ResponseMetadata ResponseMetadata
 }
 
 /* DeleteMessageBatch */
-func (q *Queue) DeleteMessageBatch(msgList []*Message) (resp *DeleteMessageBatchResponse, err error) {
+func (q *Queue) DeleteMessageBatch(entries []DeleteMessageBatchEntry) (*DeleteMessageBatchResponse, error) {
+	return q.DeleteMessageBatchContext(context.Background(), entries)
+}
+
+func (q *Queue) DeleteMessageBatchContext(ctx context.Context, entries []DeleteMessageBatchEntry) (resp *DeleteMessageBatchResponse, err error) {
+	resp = &DeleteMessageBatchResponse{}
+	for _, chunk := range chunkDeleteMessageBatchEntries(entries, maxBatchEntries) {
+		chunkResp, chunkErr := q.deleteMessageBatchChunk(ctx, chunk)
+		if chunkErr != nil {
+			return resp, chunkErr
+		}
+		resp.Successful = append(resp.Successful, chunkResp.Successful...)
+		resp.Failed = append(resp.Failed, chunkResp.Failed...)
+		resp.ResponseMetadata = chunkResp.ResponseMetadata
+	}
+
+	if len(resp.Failed) > 0 {
+		return resp, &PartialFailureError{Failed: resp.Failed}
+	}
+	return resp, nil
+}
+
+func (q *Queue) deleteMessageBatchChunk(ctx context.Context, entries []DeleteMessageBatchEntry) (resp *DeleteMessageBatchResponse, err error) {
 	resp = &DeleteMessageBatchResponse{}
 	params := makeParams("DeleteMessageBatch")
 
-	for idx, msg := range msgList {
-		idx = idx + 1
-		params[fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.Id", idx)] = fmt.Sprintf("msg-%d", idx)
-		params[fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.ReceiptHandle", idx)] = msg.ReceiptHandle
+	for idx, entry := range entries {
+		prefix := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d", idx+1)
+		params[prefix+".Id"] = entry.Id
+		params[prefix+".ReceiptHandle"] = entry.ReceiptHandle
 	}
 
-	err = q.SQS.query(q.Url, params, resp)
+	err = q.SQS.queryContext(ctx, q.Url, params, resp)
 	return
 }
 
-func (s *SQS) query(queueUrl string, params map[string]string, resp interface{}) (err error) {
+func chunkDeleteMessageBatchEntries(entries []DeleteMessageBatchEntry, size int) [][]DeleteMessageBatchEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	var chunks [][]DeleteMessageBatchEntry
+	for size < len(entries) {
+		entries, chunks = entries[size:], append(chunks, entries[0:size:size])
+	}
+	return append(chunks, entries)
+}
+
+func (s *SQS) query(queueUrl string, params map[string]string, resp interface{}) error {
+	return s.queryContext(context.Background(), queueUrl, params, resp)
+}
+
+// queryContext is query with a caller-supplied context, torn down via
+// http.NewRequestWithContext so a ctx deadline or cancellation aborts the
+// in-flight request. When s.Protocol is ProtocolJSON it delegates to
+// queryJSON instead of speaking the legacy query+XML protocol below.
+func (s *SQS) queryContext(ctx context.Context, queueUrl string, params map[string]string, resp interface{}) (err error) {
+	if s.Protocol == ProtocolJSON {
+		return s.queryJSON(ctx, queueUrl, params, resp)
+	}
+
 	params["Version"] = "2011-10-01"
 	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
 	var url_ *url.URL
@@ -328,7 +764,12 @@ func (s *SQS) query(queueUrl string, params map[string]string, resp interface{})
 		log.Printf("GET ", url_.String())
 	}
 
-	r, err := http.Get(url_.String())
+	req, err := http.NewRequestWithContext(ctx, "GET", url_.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := s.httpClient().Do(req)
 	if err != nil {
 		return err
 	}
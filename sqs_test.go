@@ -0,0 +1,213 @@
+package sqs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAddMessageAttributes(t *testing.T) {
+	params := make(map[string]string)
+	addMessageAttributes(params, "MessageAttribute", map[string]MessageAttributeValue{
+		"StringAttr": {DataType: "String", StringValue: "hello"},
+		"BinaryAttr": {DataType: "Binary", BinaryValue: []byte("bytes")},
+	})
+
+	// Map iteration order is unspecified, so find each entry by its Name
+	// value rather than assuming an index.
+	got := make(map[string]map[string]string)
+	for idx := 1; idx <= 2; idx++ {
+		prefix := fmt.Sprintf("MessageAttribute.%d", idx)
+		name, ok := params[prefix+".Name"]
+		if !ok {
+			t.Fatalf("missing %s.Name", prefix)
+		}
+		got[name] = map[string]string{
+			"DataType":    params[prefix+".Value.DataType"],
+			"StringValue": params[prefix+".Value.StringValue"],
+			"BinaryValue": params[prefix+".Value.BinaryValue"],
+		}
+	}
+
+	if got["StringAttr"]["DataType"] != "String" || got["StringAttr"]["StringValue"] != "hello" {
+		t.Errorf("StringAttr = %+v, want DataType=String StringValue=hello", got["StringAttr"])
+	}
+	if got["StringAttr"]["BinaryValue"] != "" {
+		t.Errorf("StringAttr.BinaryValue = %q, want empty", got["StringAttr"]["BinaryValue"])
+	}
+
+	wantBinary := "Ynl0ZXM=" // base64("bytes")
+	if got["BinaryAttr"]["DataType"] != "Binary" || got["BinaryAttr"]["BinaryValue"] != wantBinary {
+		t.Errorf("BinaryAttr = %+v, want DataType=Binary BinaryValue=%s", got["BinaryAttr"], wantBinary)
+	}
+	if got["BinaryAttr"]["StringValue"] != "" {
+		t.Errorf("BinaryAttr.StringValue = %q, want empty", got["BinaryAttr"]["StringValue"])
+	}
+}
+
+func TestChunkSendMessageBatchEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []SendMessageBatchEntry
+		size    int
+		want    int // number of chunks
+	}{
+		{"empty", nil, 10, 0},
+		{"under size", []SendMessageBatchEntry{{Id: "1"}, {Id: "2"}}, 10, 1},
+		{"exact multiple", make([]SendMessageBatchEntry, 20), 10, 2},
+		{"remainder", make([]SendMessageBatchEntry, 25), 10, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkSendMessageBatchEntries(tt.entries, tt.size)
+			if len(chunks) != tt.want {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.want)
+			}
+			var total int
+			for _, c := range chunks {
+				if len(c) > tt.size {
+					t.Errorf("chunk of size %d exceeds max %d", len(c), tt.size)
+				}
+				total += len(c)
+			}
+			if total != len(tt.entries) {
+				t.Errorf("chunks cover %d entries, want %d", total, len(tt.entries))
+			}
+		})
+	}
+}
+
+func TestChunkDeleteMessageBatchEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []DeleteMessageBatchEntry
+		size    int
+		want    int
+	}{
+		{"empty", nil, 10, 0},
+		{"under size", []DeleteMessageBatchEntry{{Id: "1"}}, 10, 1},
+		{"exact multiple", make([]DeleteMessageBatchEntry, 20), 10, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkDeleteMessageBatchEntries(tt.entries, tt.size)
+			if len(chunks) != tt.want {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageAttributeValueUnmarshalXML(t *testing.T) {
+	tests := []struct {
+		name     string
+		xmlBody  string
+		wantData string
+		wantStr  string
+		wantBin  []byte
+		wantErr  bool
+	}{
+		{
+			name: "string value",
+			xmlBody: `<Value><DataType>String</DataType>` +
+				`<StringValue>hello</StringValue></Value>`,
+			wantData: "String",
+			wantStr:  "hello",
+		},
+		{
+			name: "binary value round-trips through base64",
+			xmlBody: `<Value><DataType>Binary</DataType>` +
+				`<BinaryValue>Ynl0ZXM=</BinaryValue></Value>`,
+			wantData: "Binary",
+			wantBin:  []byte("bytes"),
+		},
+		{
+			name:     "absent binary value stays nil",
+			xmlBody:  `<Value><DataType>String</DataType><StringValue>x</StringValue></Value>`,
+			wantData: "String",
+			wantStr:  "x",
+			wantBin:  nil,
+		},
+		{
+			name:    "invalid base64 errors",
+			xmlBody: `<Value><DataType>Binary</DataType><BinaryValue>not-valid-base64!</BinaryValue></Value>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v MessageAttributeValue
+			err := xml.Unmarshal([]byte(tt.xmlBody), &v)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if v.DataType != tt.wantData {
+				t.Errorf("DataType = %q, want %q", v.DataType, tt.wantData)
+			}
+			if v.StringValue != tt.wantStr {
+				t.Errorf("StringValue = %q, want %q", v.StringValue, tt.wantStr)
+			}
+			if !reflect.DeepEqual(v.BinaryValue, tt.wantBin) {
+				t.Errorf("BinaryValue = %v, want %v", v.BinaryValue, tt.wantBin)
+			}
+		})
+	}
+}
+
+func TestAttributeListUnmarshalJSON(t *testing.T) {
+	var al AttributeList
+	err := al.UnmarshalJSON([]byte(`{"MessageGroupId":"group-1","SequenceNumber":"42"}`))
+	if err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	if len(al) != 2 {
+		t.Fatalf("got %d attributes, want 2", len(al))
+	}
+
+	got := make(map[string]string, len(al))
+	for _, a := range al {
+		got[a.Name] = a.Value
+	}
+	want := map[string]string{"MessageGroupId": "group-1", "SequenceNumber": "42"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMessageAttributeListUnmarshalJSON(t *testing.T) {
+	var mal MessageAttributeList
+	err := mal.UnmarshalJSON([]byte(`{"Foo":{"DataType":"String","StringValue":"bar"}}`))
+	if err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	if len(mal) != 1 {
+		t.Fatalf("got %d message attributes, want 1", len(mal))
+	}
+	if mal[0].Name != "Foo" || mal[0].Value.StringValue != "bar" {
+		t.Errorf("got %+v, want Name=Foo Value.StringValue=bar", mal[0])
+	}
+}
+
+func TestMessageAttributeHelper(t *testing.T) {
+	m := &Message{
+		MessageAttribute: MessageAttributeList{
+			{Name: "sqs-codec-base64", Value: MessageAttributeValue{DataType: "String", StringValue: "true"}},
+		},
+	}
+	if got := m.messageAttribute("sqs-codec-base64"); got != "true" {
+		t.Errorf("messageAttribute(%q) = %q, want %q", "sqs-codec-base64", got, "true")
+	}
+	if got := m.messageAttribute("missing"); got != "" {
+		t.Errorf("messageAttribute(missing) = %q, want empty", got)
+	}
+}